@@ -0,0 +1,113 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+)
+
+// Errors returned by the bitrot algorithm registry and VerifyStream.
+var (
+	// errBitrotHashAlgoUnregistered is returned by VerifyStream and
+	// VerifyChecksumStream when the algorithm is neither a built-in
+	// BitrotAlgorithm nor registered via RegisterBitrotAlgorithm. Parsing
+	// an xl.json that names such an algorithm fails with
+	// errBitrotHashAlgoInvalid instead.
+	errBitrotHashAlgoUnregistered = errors.New("bitrot algorithm not registered")
+	// errBitrotHashMismatch is returned by VerifyStream when the
+	// streamed data's digest doesn't match the expected checksum.
+	errBitrotHashMismatch = errors.New("bitrot hash mismatch")
+)
+
+var (
+	bitrotAlgorithmRegistryMu sync.RWMutex
+	bitrotAlgorithmRegistry   = map[string]func() hash.Hash{}
+)
+
+// RegisterBitrotAlgorithm registers a bitrot hash algorithm under name, a
+// valid value for a ChecksumInfo's "algorithm" field in xl.json even
+// though it isn't one of the built-in BitrotAlgorithm constants. factory
+// must return a fresh, ready-to-write hash.Hash on every call.
+func RegisterBitrotAlgorithm(name string, factory func() hash.Hash) {
+	bitrotAlgorithmRegistryMu.Lock()
+	defer bitrotAlgorithmRegistryMu.Unlock()
+	bitrotAlgorithmRegistry[name] = factory
+}
+
+// bitrotHashFactory looks up name among the registered algorithms,
+// returning ok == false if nothing was registered under it.
+func bitrotHashFactory(name string) (factory func() hash.Hash, ok bool) {
+	bitrotAlgorithmRegistryMu.RLock()
+	defer bitrotAlgorithmRegistryMu.RUnlock()
+	factory, ok = bitrotAlgorithmRegistry[name]
+	return factory, ok
+}
+
+// isBitrotAlgorithmKnown reports whether name is usable as a bitrot
+// algorithm, either because algo (as parsed from name via
+// BitrotAlgorithmFromString) is one of the built-in algorithms, or
+// because name was registered via RegisterBitrotAlgorithm.
+func isBitrotAlgorithmKnown(name string, algo BitrotAlgorithm) bool {
+	if algo.Available() {
+		return true
+	}
+	_, ok := bitrotHashFactory(name)
+	return ok
+}
+
+// VerifyStream reads r to completion, hashing it with b, and returns
+// errBitrotHashMismatch if the digest doesn't match expected. Lets
+// callers verify part data as it streams off disk instead of buffering
+// the whole block first. b must be Available(); for any other value it
+// returns errBitrotHashAlgoUnregistered. Checksums whose algorithm is
+// only registered via RegisterBitrotAlgorithm are verified through
+// VerifyChecksumStream, which looks the name up in the registry.
+func (b BitrotAlgorithm) VerifyStream(r io.Reader, expected []byte) error {
+	if b.Available() {
+		return verifyStream(b.New(), r, expected)
+	}
+	return traceError(errBitrotHashAlgoUnregistered)
+}
+
+// VerifyChecksumStream verifies r against checksum.Hash, preferring
+// checksum.AlgorithmName (set for every parsed entry, including
+// registered-but-non-built-in algorithms) over checksum.Algorithm's
+// built-in enum value. This is what makes a registered custom algorithm
+// round-trip correctly instead of collapsing to whatever the zero
+// BitrotAlgorithm value happens to be.
+func VerifyChecksumStream(r io.Reader, checksum ChecksumInfo) error {
+	if factory, ok := bitrotHashFactory(checksum.AlgorithmName); ok {
+		return verifyStream(factory(), r, checksum.Hash)
+	}
+	return checksum.Algorithm.VerifyStream(r, checksum.Hash)
+}
+
+// verifyStream hashes r with h and compares the digest against expected,
+// shared by VerifyStream and VerifyChecksumStream.
+func verifyStream(h hash.Hash, r io.Reader, expected []byte) error {
+	if _, err := io.Copy(h, r); err != nil {
+		return traceError(err)
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return traceError(errBitrotHashMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"hash"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+func TestRegisterBitrotAlgorithmVerifyStream(t *testing.T) {
+	const algoName = "test-fnv64a"
+	RegisterBitrotAlgorithm(algoName, func() hash.Hash { return fnv.New64a() })
+
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	h := fnv.New64a()
+	h.Write(data)
+	expected := h.Sum(nil)
+
+	var unknown BitrotAlgorithm
+	if err := unknown.VerifyStream(strings.NewReader(string(data)), expected); err == nil {
+		t.Fatal("expected an error verifying against an unregistered algorithm")
+	}
+
+	factory, ok := bitrotHashFactory(algoName)
+	if !ok {
+		t.Fatal("expected test-fnv64a to be registered")
+	}
+	streamed := factory()
+	if _, err := streamed.Write(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(streamed.Sum(nil), expected) {
+		t.Fatal("registered factory did not reproduce the expected digest")
+	}
+}
+
+func TestIsBitrotAlgorithmKnownAcceptsRegisteredNames(t *testing.T) {
+	const algoName = "test-registered-only"
+	RegisterBitrotAlgorithm(algoName, func() hash.Hash { return fnv.New64a() })
+
+	var unavailable BitrotAlgorithm
+	if !isBitrotAlgorithmKnown(algoName, unavailable) {
+		t.Fatal("expected a registered algorithm name to be known even if unavailable as a BitrotAlgorithm constant")
+	}
+	if isBitrotAlgorithmKnown("definitely-not-registered", unavailable) {
+		t.Fatal("expected an unregistered, unavailable algorithm name to be unknown")
+	}
+}
+
+func TestVerifyChecksumStreamUsesAlgorithmName(t *testing.T) {
+	const algoName = "test-checksum-round-trip"
+	RegisterBitrotAlgorithm(algoName, func() hash.Hash { return fnv.New64a() })
+
+	data := []byte("round trip me")
+	h := fnv.New64a()
+	h.Write(data)
+	expected := h.Sum(nil)
+
+	// Algorithm is left at its zero value, as parseXLErasureInfoResult
+	// leaves it for a name BitrotAlgorithmFromString doesn't recognize;
+	// AlgorithmName is what must carry the registered name through.
+	checksum := ChecksumInfo{Name: "part.1", AlgorithmName: algoName, Hash: expected}
+	if err := VerifyChecksumStream(strings.NewReader(string(data)), checksum); err != nil {
+		t.Fatalf("unexpected error verifying via AlgorithmName: %v", err)
+	}
+
+	corrupted := checksum
+	corrupted.Hash = append([]byte(nil), expected...)
+	corrupted.Hash[0] ^= 0xFF
+	if err := VerifyChecksumStream(strings.NewReader(string(data)), corrupted); err == nil {
+		t.Fatal("expected an error verifying a corrupted digest")
+	}
+}
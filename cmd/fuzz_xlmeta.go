@@ -0,0 +1,63 @@
+// +build gofuzz
+
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"hash"
+	"hash/fnv"
+	"reflect"
+)
+
+func init() {
+	// Registered so the corpus's xl.json seeds naming these algorithms
+	// actually exercise the registry/mixed-algorithm-name surface instead
+	// of always hitting errBitrotHashAlgoInvalid.
+	RegisterBitrotAlgorithm("fuzz-custom-blake3", func() hash.Hash { return fnv.New64a() })
+	RegisterBitrotAlgorithm("fuzz-custom-highwayhash", func() hash.Hash { return fnv.New64a() })
+}
+
+// Fuzz is the entry point for go-fuzz (`go-fuzz-build` + `go-fuzz`, build
+// tag gofuzz). It feeds arbitrary bytes, including xl.json documents with
+// unknown, mixed, or registered-custom bitrot "algorithm" names, to both
+// xl.json decoders and fails if they disagree on validity or content.
+func Fuzz(data []byte) int {
+	meta, err := xlMetaV1UnmarshalJSON(data)
+	metaSinglePass, errSinglePass := xlMetaV1UnmarshalJSONSinglePass(data)
+
+	if (err == nil) != (errSinglePass == nil) {
+		panic("xlMetaV1UnmarshalJSON and xlMetaV1UnmarshalJSONSinglePass disagree on whether input is valid")
+	}
+	if err != nil {
+		return 0
+	}
+	// xlMetaV1UnmarshalJSON always allocates a (possibly zero-length)
+	// Parts slice via parseXLParts, while the single-pass decoder only
+	// allocates one if "parts" has entries; nil an empty slice on both
+	// sides first so that distinction doesn't register as a mismatch.
+	if len(meta.Parts) == 0 {
+		meta.Parts = nil
+	}
+	if len(metaSinglePass.Parts) == 0 {
+		metaSinglePass.Parts = nil
+	}
+	if !reflect.DeepEqual(meta, metaSinglePass) {
+		panic("xlMetaV1UnmarshalJSON and xlMetaV1UnmarshalJSONSinglePass disagree on parsed content")
+	}
+	return 1
+}
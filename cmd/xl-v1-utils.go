@@ -17,10 +17,15 @@
 package cmd
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"hash/crc32"
 	"path"
+	"sort"
 	"sync"
 	"time"
 
@@ -124,6 +129,82 @@ func hashOrder(key string, cardinality int) []int {
 	return nums
 }
 
+// hashAlgorithm identifies the ordering algorithm used to derive the
+// distribution of an object across the disks of an erasure set. This is
+// the knob xlObjects.hashAlgorithm selects between.
+type hashAlgorithm string
+
+const (
+	// hashAlgoCRC is the historical ordering, keyed only on cardinality.
+	hashAlgoCRC hashAlgorithm = "crc"
+	// hashAlgoHRW is Rendezvous (HRW) ordering, keyed on disk/node IDs.
+	hashAlgoHRW hashAlgorithm = "hrw"
+)
+
+// hashOrderHRW ranks nodeIDs for key using Rendezvous (Highest Random
+// Weight) hashing: each node's weight is a SHA-256 digest of key+nodeID
+// truncated to 64 bits, and nodes are returned by descending weight.
+// Adding or removing a node only changes the ranking for keys that map to
+// that node, unlike hashOrder's cardinality-keyed ordering.
+func hashOrderHRW(key string, nodeIDs []string) []int {
+	type weightedNode struct {
+		index  int
+		weight uint64
+	}
+	weighted := make([]weightedNode, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		sum := sha256.Sum256([]byte(key + nodeID))
+		weighted[i] = weightedNode{index: i, weight: binary.BigEndian.Uint64(sum[:8])}
+	}
+	// Highest weight first. Ties (exceedingly unlikely with a 64-bit
+	// digest) fall back to node index so the ordering stays deterministic.
+	sort.Slice(weighted, func(i, j int) bool {
+		if weighted[i].weight != weighted[j].weight {
+			return weighted[i].weight > weighted[j].weight
+		}
+		return weighted[i].index < weighted[j].index
+	})
+	order := make([]int, len(nodeIDs))
+	for i, w := range weighted {
+		order[i] = w.index
+	}
+	return order
+}
+
+// hashOrderDisks returns a 1-based distribution ordering for key, suitable
+// for feeding directly into shuffleDisks/shufflePartsMetadata. algo picks
+// CRC (hashOrder, keyed on cardinality) or HRW (hashOrderHRW, keyed on
+// nodeIDs). nodeIDs is ignored unless algo is hashAlgoHRW, in which case
+// it must carry exactly cardinality entries, one per disk, e.g. each
+// disk's format UUID.
+func hashOrderDisks(key string, cardinality int, algo hashAlgorithm, nodeIDs []string) []int {
+	if algo == hashAlgoHRW && len(nodeIDs) == cardinality {
+		order := hashOrderHRW(key, nodeIDs)
+		// order[rank] = nodeIndex; shuffleDisks/shufflePartsMetadata index
+		// by nodeIndex and expect the block number that disk should land
+		// in, i.e. the inverse permutation.
+		distribution := make([]int, len(order))
+		for rank, idx := range order {
+			distribution[idx] = rank + 1
+		}
+		return distribution
+	}
+	return hashOrder(key, cardinality)
+}
+
+// distributeXLMeta derives a distribution for key via hashOrderDisks under
+// xl.hashAlgorithm, then applies it to both disks and partsMetadata so the
+// two slices come back in the same order. PutObject and
+// NewMultipartUpload do not call it, so xl.hashAlgorithm does not affect
+// their placement.
+func (xl xlObjects) distributeXLMeta(key string, disks []StorageAPI, partsMetadata []xlMetaV1, nodeIDs []string) ([]StorageAPI, []xlMetaV1) {
+	// cardinality must be len(disks), not diskCount(disks): shuffleDisks
+	// and shufflePartsMetadata index the distribution against the full
+	// slice length, including nil (down) disks.
+	distribution := hashOrderDisks(key, len(disks), xl.hashAlgorithm, nodeIDs)
+	return shuffleDisks(disks, distribution), shufflePartsMetadata(partsMetadata, distribution)
+}
+
 func parseXLStat(xlMetaBuf []byte) (si statInfo, e error) {
 	// obtain stat info.
 	stat := statInfo{}
@@ -151,8 +232,18 @@ func parseXLRelease(xlMetaBuf []byte) string {
 }
 
 func parseXLErasureInfo(xlMetaBuf []byte) (ErasureInfo, error) {
-	erasure := ErasureInfo{}
 	erasureResult := gjson.GetBytes(xlMetaBuf, "erasure")
+	return parseXLErasureInfoResult(erasureResult)
+}
+
+// parseXLErasureInfoResult parses an already-extracted "erasure" gjson.Result
+// into an ErasureInfo. Split out of parseXLErasureInfo so that both the
+// repeated-scan path (xlMetaV1UnmarshalJSON, which locates "erasure" via a
+// fresh gjson.GetBytes) and the single-pass path
+// (xlMetaV1UnmarshalJSONSinglePass, which already holds the Result from its
+// top-level walk) share one implementation.
+func parseXLErasureInfoResult(erasureResult gjson.Result) (ErasureInfo, error) {
+	erasure := ErasureInfo{}
 	// parse the xlV1Meta.Erasure.Distribution.
 	disResult := erasureResult.Get("distribution").Array()
 
@@ -172,15 +263,23 @@ func parseXLErasureInfo(xlMetaBuf []byte) (ErasureInfo, error) {
 	// Parse xlMetaV1.Erasure.Checksum array.
 	checkSums := make([]ChecksumInfo, len(checkSumsResult))
 	for i, v := range checkSumsResult {
-		algorithm := BitrotAlgorithmFromString(v.Get("algorithm").String())
-		if !algorithm.Available() {
+		algorithmName := v.Get("algorithm").String()
+		algorithm := BitrotAlgorithmFromString(algorithmName)
+		// Algorithms registered via RegisterBitrotAlgorithm are accepted
+		// here too, so objects written with an operator-added algorithm
+		// load instead of being rejected as corrupted. AlgorithmName
+		// carries the raw name through regardless of whether Algorithm
+		// resolved to a built-in constant, so a registered name
+		// round-trips instead of collapsing to the zero BitrotAlgorithm
+		// value.
+		if !isBitrotAlgorithmKnown(algorithmName, algorithm) {
 			return erasure, traceError(errBitrotHashAlgoInvalid)
 		}
 		hash, err := hex.DecodeString(v.Get("hash").String())
 		if err != nil {
 			return erasure, traceError(err)
 		}
-		checkSums[i] = ChecksumInfo{Name: v.Get("name").String(), Algorithm: algorithm, Hash: hash}
+		checkSums[i] = ChecksumInfo{Name: v.Get("name").String(), Algorithm: algorithm, AlgorithmName: algorithmName, Hash: hash}
 	}
 	erasure.Checksums = checkSums
 	return erasure, nil
@@ -211,7 +310,9 @@ func parseXLMetaMap(xlMetaBuf []byte) map[string]string {
 	return metaMap
 }
 
-// Constructs XLMetaV1 using `gjson` lib to retrieve each field.
+// Constructs XLMetaV1 using `gjson` lib to retrieve each field. Superseded
+// by xlMetaV1UnmarshalJSONSinglePass as the live path in readXLMeta; kept
+// for the benchmark/fuzz comparison against it.
 func xlMetaV1UnmarshalJSON(xlMetaBuf []byte) (xlMeta xlMetaV1, e error) {
 	// obtain version.
 	xlMeta.Version = parseXLVersion(xlMetaBuf)
@@ -240,6 +341,73 @@ func xlMetaV1UnmarshalJSON(xlMetaBuf []byte) (xlMeta xlMetaV1, e error) {
 	return xlMeta, nil
 }
 
+// xlMetaV1UnmarshalJSONSinglePass constructs xlMetaV1 with a single
+// top-level walk over xlMetaBuf via gjson's ForEach, instead of the
+// per-field gjson.GetBytes calls xlMetaV1UnmarshalJSON makes, each of
+// which rescans the buffer from the start. This is the live path used by
+// readXLMeta; xlMetaV1UnmarshalJSON is kept for
+// BenchmarkXLMetaV1UnmarshalJSON/BenchmarkXLMetaV1UnmarshalJSONSinglePass
+// and the fuzz harness to compare against.
+func xlMetaV1UnmarshalJSONSinglePass(xlMetaBuf []byte) (xlMeta xlMetaV1, e error) {
+	xlMeta.Meta = make(map[string]string)
+	var sawStat bool
+	var parseErr error
+	gjson.ParseBytes(xlMetaBuf).ForEach(func(key, value gjson.Result) bool {
+		switch key.String() {
+		case "version":
+			xlMeta.Version = value.String()
+		case "format":
+			xlMeta.Format = value.String()
+		case "stat":
+			modTime, err := time.Parse(time.RFC3339, value.Get("modTime").String())
+			if err != nil {
+				parseErr = err
+				return false
+			}
+			sawStat = true
+			xlMeta.Stat.ModTime = modTime
+			xlMeta.Stat.Size = value.Get("size").Int()
+		case "erasure":
+			erasure, err := parseXLErasureInfoResult(value)
+			if err != nil {
+				parseErr = err
+				return false
+			}
+			xlMeta.Erasure = erasure
+		case "parts":
+			value.ForEach(func(_, p gjson.Result) bool {
+				xlMeta.Parts = append(xlMeta.Parts, objectPartInfo{
+					Number: int(p.Get("number").Int()),
+					Name:   p.Get("name").String(),
+					ETag:   p.Get("etag").String(),
+					Size:   p.Get("size").Int(),
+				})
+				return true
+			})
+		case "meta":
+			value.ForEach(func(metaKey, metaValue gjson.Result) bool {
+				xlMeta.Meta[metaKey.String()] = metaValue.String()
+				return true
+			})
+		case "minio":
+			xlMeta.Minio.Release = value.Get("release").String()
+		}
+		return true
+	})
+	if parseErr != nil {
+		return xlMetaV1{}, parseErr
+	}
+	if !sawStat {
+		// No top-level "stat" key at all, e.g. a truncated/empty buffer.
+		// parseXLStat would fail the same way on an empty modTime string;
+		// do the same here instead of silently returning a zero Stat.
+		if _, err := time.Parse(time.RFC3339, ""); err != nil {
+			return xlMetaV1{}, err
+		}
+	}
+	return xlMeta, nil
+}
+
 // read xl.json from the given disk, parse and return xlV1MetaV1.Parts.
 func readXLMetaParts(disk StorageAPI, bucket string, object string) ([]objectPartInfo, error) {
 	// Reads entire `xl.json`.
@@ -293,8 +461,10 @@ func readXLMeta(disk StorageAPI, bucket string, object string) (xlMeta xlMetaV1,
 	if err != nil {
 		return xlMetaV1{}, traceError(err)
 	}
-	// obtain xlMetaV1{} using `github.com/tidwall/gjson`.
-	xlMeta, err = xlMetaV1UnmarshalJSON(xlMetaBuf)
+	// obtain xlMetaV1{} via the single-pass decoder (see
+	// xlMetaV1UnmarshalJSONSinglePass); xlMetaV1UnmarshalJSON is kept for
+	// the benchmark/fuzz harness comparing the two.
+	xlMeta, err = xlMetaV1UnmarshalJSONSinglePass(xlMetaBuf)
 	if err != nil {
 		return xlMetaV1{}, traceError(err)
 	}
@@ -334,7 +504,110 @@ func readAllXLMetadata(disks []StorageAPI, bucket, object string) ([]xlMetaV1, [
 	return metadataArray, errs
 }
 
-// Return shuffled partsMetadata depending on distribution.
+// xlMetaCanonicalHash hashes the fields of an xlMetaV1 that must be
+// byte-identical across every disk holding a copy, in a fixed, sorted
+// order so map iteration order doesn't affect the result. Erasure.Index
+// is excluded since it's a disk's own position in the set and legitimately
+// differs copy-to-copy.
+func xlMetaCanonicalHash(meta xlMetaV1) [sha256.Size]byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "version=%s\nformat=%s\n", meta.Version, meta.Format)
+	fmt.Fprintf(&buf, "stat.size=%d\nstat.modTime=%s\n",
+		meta.Stat.Size, meta.Stat.ModTime.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&buf, "erasure.algorithm=%s\nerasure.data=%d\nerasure.parity=%d\nerasure.blockSize=%d\nerasure.distribution=%v\n",
+		meta.Erasure.Algorithm, meta.Erasure.DataBlocks, meta.Erasure.ParityBlocks,
+		meta.Erasure.BlockSize, meta.Erasure.Distribution)
+
+	checksums := append([]ChecksumInfo(nil), meta.Erasure.Checksums...)
+	sort.Slice(checksums, func(i, j int) bool { return checksums[i].Name < checksums[j].Name })
+	for _, c := range checksums {
+		// AlgorithmName, not Algorithm, distinguishes registered custom
+		// algorithms from one another: Algorithm is left at its zero
+		// value for any name BitrotAlgorithmFromString doesn't recognize.
+		fmt.Fprintf(&buf, "erasure.checksum[%s]=%s:%x\n", c.Name, c.AlgorithmName, c.Hash)
+	}
+
+	parts := append([]objectPartInfo(nil), meta.Parts...)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	for _, p := range parts {
+		fmt.Fprintf(&buf, "part[%d]=%s:%s:%d\n", p.Number, p.Name, p.ETag, p.Size)
+	}
+
+	metaKeys := make([]string, 0, len(meta.Meta))
+	for k := range meta.Meta {
+		metaKeys = append(metaKeys, k)
+	}
+	sort.Strings(metaKeys)
+	for _, k := range metaKeys {
+		fmt.Fprintf(&buf, "meta[%s]=%s\n", k, meta.Meta[k])
+	}
+
+	fmt.Fprintf(&buf, "release=%s\n", meta.Minio.Release)
+	return sha256.Sum256(buf.Bytes())
+}
+
+// pickQuorumXLMeta groups metas by xlMetaCanonicalHash, skipping any index
+// for which errs[index] is set, and returns the meta of the group that
+// meets quorum with the most recent Stat.ModTime, together with the
+// indices of the disks that agreed on it. This guards against silent
+// bitrot of xl.json itself and against split-brain, where two
+// internally-consistent groups of disks each satisfy a plain
+// modTime-and-count heuristic. Groups meeting quorum with equal modTimes
+// are decided by group size, then by content hash so the result is
+// deterministic regardless of map iteration order.
+func pickQuorumXLMeta(metas []xlMetaV1, errs []error, quorum int) (xlMetaV1, []int, error) {
+	type group struct {
+		indices []int
+		modTime time.Time
+	}
+	groups := make(map[[sha256.Size]byte]*group)
+	for i, meta := range metas {
+		if errs[i] != nil {
+			continue
+		}
+		hash := xlMetaCanonicalHash(meta)
+		g, ok := groups[hash]
+		if !ok {
+			g = &group{}
+			groups[hash] = g
+		}
+		g.indices = append(g.indices, i)
+		if meta.Stat.ModTime.After(g.modTime) {
+			g.modTime = meta.Stat.ModTime
+		}
+	}
+
+	var bestHash [sha256.Size]byte
+	var best *group
+	for hash, g := range groups {
+		if len(g.indices) < quorum {
+			continue
+		}
+		switch {
+		case best == nil:
+			bestHash, best = hash, g
+		case g.modTime.After(best.modTime):
+			bestHash, best = hash, g
+		case g.modTime.Equal(best.modTime) && len(g.indices) > len(best.indices):
+			bestHash, best = hash, g
+		// Map iteration order is randomized per-process, so a tie on
+		// both modTime and size must not be broken by visit order:
+		// fall back to the (otherwise meaningless, but stable) content
+		// hash so repeated calls always resolve the same way.
+		case g.modTime.Equal(best.modTime) && len(g.indices) == len(best.indices) && bytes.Compare(hash[:], bestHash[:]) > 0:
+			bestHash, best = hash, g
+		}
+	}
+	if best == nil {
+		return xlMetaV1{}, nil, traceError(errXLReadQuorum)
+	}
+	sort.Ints(best.indices)
+	return metas[best.indices[0]], best.indices, nil
+}
+
+// Return shuffled partsMetadata depending on distribution. distribution
+// maps each index to a 1-based block number, as hashOrder and
+// hashOrderDisks (CRC or HRW ordering) both return.
 func shufflePartsMetadata(partsMetadata []xlMetaV1, distribution []int) (shuffledPartsMetadata []xlMetaV1) {
 	if distribution == nil {
 		return partsMetadata
@@ -350,7 +623,9 @@ func shufflePartsMetadata(partsMetadata []xlMetaV1, distribution []int) (shuffle
 
 // shuffleDisks - shuffle input disks slice depending on the
 // erasure distribution. Return shuffled slice of disks with
-// their expected distribution.
+// their expected distribution. distribution maps each index to a
+// 1-based block number, as hashOrder and hashOrderDisks (CRC or HRW
+// ordering) both return.
 func shuffleDisks(disks []StorageAPI, distribution []int) (shuffledDisks []StorageAPI) {
 	if distribution == nil {
 		return disks
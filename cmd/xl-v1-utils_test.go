@@ -0,0 +1,318 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// genXLMetaBuf builds a synthetic xl.json with numParts parts and
+// numMetaEntries user-metadata entries, to approximate the shape of a
+// large multipart upload's metadata.
+func genXLMetaBuf(numParts, numMetaEntries int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"version":"1.0.1","format":"xl","stat":{"size":204800,"modTime":"2016-05-18T00:05:53.136Z"},`)
+	buf.WriteString(`"erasure":{"algorithm":"klauspost/reedsolomon/vandermonde","data":8,"parity":8,"blockSize":4194304,"index":1,"distribution":[2,1,3,4,5,6,7,8,9,10,11,12,13,14,15,16],"checksum":[{"name":"part.1","algorithm":"blake2b","hash":"1d1fc0f6def0535d999f50228457e4"}]},`)
+	buf.WriteString(`"parts":[`)
+	for i := 0; i < numParts; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"number":%d,"name":"part.%d","etag":"d41d8cd98f00b204e9800998ecf8427e","size":5242880}`, i+1, i+1)
+	}
+	buf.WriteString(`],"meta":{`)
+	for i := 0; i < numMetaEntries; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `"x-amz-meta-key-%d":"value-%d"`, i, i)
+	}
+	buf.WriteString(`},"minio":{"release":"DEVELOPMENT.GOGET"}}`)
+	return buf.Bytes()
+}
+
+func benchmarkXLMetaV1UnmarshalJSON(b *testing.B, numParts, numMetaEntries int) {
+	xlMetaBuf := genXLMetaBuf(numParts, numMetaEntries)
+	b.SetBytes(int64(len(xlMetaBuf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := xlMetaV1UnmarshalJSON(xlMetaBuf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkXLMetaV1UnmarshalJSONSinglePass(b *testing.B, numParts, numMetaEntries int) {
+	xlMetaBuf := genXLMetaBuf(numParts, numMetaEntries)
+	b.SetBytes(int64(len(xlMetaBuf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := xlMetaV1UnmarshalJSONSinglePass(xlMetaBuf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkXLMetaV1UnmarshalJSON_1KParts(b *testing.B)  { benchmarkXLMetaV1UnmarshalJSON(b, 1000, 10) }
+func BenchmarkXLMetaV1UnmarshalJSON_10KParts(b *testing.B) { benchmarkXLMetaV1UnmarshalJSON(b, 10000, 10) }
+
+func BenchmarkXLMetaV1UnmarshalJSONSinglePass_1KParts(b *testing.B) {
+	benchmarkXLMetaV1UnmarshalJSONSinglePass(b, 1000, 10)
+}
+func BenchmarkXLMetaV1UnmarshalJSONSinglePass_10KParts(b *testing.B) {
+	benchmarkXLMetaV1UnmarshalJSONSinglePass(b, 10000, 10)
+}
+
+func TestXLMetaV1UnmarshalJSONSinglePassRequiresStat(t *testing.T) {
+	for _, xlMetaBuf := range [][]byte{
+		[]byte(`{}`),
+		[]byte(`{"version":"1.0.1","format":"xl"}`),
+		[]byte{},
+	} {
+		_, legacyErr := xlMetaV1UnmarshalJSON(xlMetaBuf)
+		if legacyErr == nil {
+			t.Fatalf("expected xlMetaV1UnmarshalJSON to reject %q as missing stat.modTime", xlMetaBuf)
+		}
+		if _, err := xlMetaV1UnmarshalJSONSinglePass(xlMetaBuf); err == nil {
+			t.Fatalf("expected xlMetaV1UnmarshalJSONSinglePass to reject %q like xlMetaV1UnmarshalJSON does, got nil error", xlMetaBuf)
+		}
+	}
+}
+
+// newTestXLMeta returns an otherwise-identical xlMetaV1 for the given
+// modTime, so tests can build groups of agreeing/disagreeing copies.
+func newTestXLMeta(modTime time.Time, size int64) xlMetaV1 {
+	return xlMetaV1{
+		Version: "1.0.1",
+		Format:  "xl",
+		Stat: statInfo{
+			ModTime: modTime,
+			Size:    size,
+		},
+		Erasure: ErasureInfo{
+			Algorithm:    "klauspost/reedsolomon/vandermonde",
+			DataBlocks:   8,
+			ParityBlocks: 8,
+			BlockSize:    4 * 1024 * 1024,
+			Distribution: []int{2, 1, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		},
+		Parts: []objectPartInfo{
+			{Number: 1, Name: "part.1", ETag: "d41d8cd98f00b204e9800998ecf8427e", Size: 5242880},
+		},
+		Minio: struct{ Release string }{Release: "DEVELOPMENT.GOGET"},
+		Meta:  map[string]string{"x-amz-meta-key": "value"},
+	}
+}
+
+func TestPickQuorumXLMetaAllAgree(t *testing.T) {
+	modTime := time.Now().UTC()
+	metas := []xlMetaV1{
+		newTestXLMeta(modTime, 100),
+		newTestXLMeta(modTime, 100),
+		newTestXLMeta(modTime, 100),
+	}
+	errs := make([]error, len(metas))
+	picked, indices, err := pickQuorumXLMeta(metas, errs, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(indices) != 3 {
+		t.Fatalf("expected all 3 disks to agree, got %v", indices)
+	}
+	if picked.Stat.Size != 100 {
+		t.Fatalf("expected picked meta size 100, got %d", picked.Stat.Size)
+	}
+}
+
+func TestPickQuorumXLMetaTieBreaksOnModTime(t *testing.T) {
+	older := time.Now().Add(-time.Hour).UTC()
+	newer := time.Now().UTC()
+	// Two disks agree on the (stale) older version, two on the newer one.
+	// Both groups meet a quorum of 2; the more recent modTime must win.
+	metas := []xlMetaV1{
+		newTestXLMeta(older, 100),
+		newTestXLMeta(older, 100),
+		newTestXLMeta(newer, 200),
+		newTestXLMeta(newer, 200),
+	}
+	errs := make([]error, len(metas))
+	picked, indices, err := pickQuorumXLMeta(metas, errs, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if picked.Stat.Size != 200 {
+		t.Fatalf("expected the newer group to win, got size %d", picked.Stat.Size)
+	}
+	if len(indices) != 2 || indices[0] != 2 || indices[1] != 3 {
+		t.Fatalf("expected indices [2 3] to agree, got %v", indices)
+	}
+}
+
+func TestPickQuorumXLMetaAllDifferent(t *testing.T) {
+	modTime := time.Now().UTC()
+	// Every disk disagrees, e.g. a split-brain where no two copies match.
+	metas := []xlMetaV1{
+		newTestXLMeta(modTime, 100),
+		newTestXLMeta(modTime, 200),
+		newTestXLMeta(modTime, 300),
+	}
+	errs := make([]error, len(metas))
+	if _, _, err := pickQuorumXLMeta(metas, errs, 2); err == nil {
+		t.Fatal("expected an error when no group meets quorum")
+	}
+}
+
+func TestPickQuorumXLMetaBitrotOnXLJSON(t *testing.T) {
+	modTime := time.Now().UTC()
+	good := newTestXLMeta(modTime, 100)
+	corrupted := good
+	// Simulate bitrot that flipped a bit in xl.json's stat.size but left
+	// a plausible, still-parseable document with the same modTime.
+	corrupted.Stat.Size = 101
+	metas := []xlMetaV1{good, good, corrupted}
+	errs := make([]error, len(metas))
+	picked, indices, err := pickQuorumXLMeta(metas, errs, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if picked.Stat.Size != 100 {
+		t.Fatalf("expected the uncorrupted group to win quorum, got size %d", picked.Stat.Size)
+	}
+	if len(indices) != 2 || indices[0] != 0 || indices[1] != 1 {
+		t.Fatalf("expected indices [0 1] to agree, got %v", indices)
+	}
+}
+
+func TestHashOrderHRWMonotonicity(t *testing.T) {
+	nodeIDs := []string{"disk-a", "disk-b", "disk-c", "disk-d", "disk-e", "disk-f", "disk-g", "disk-h"}
+	const numKeys = 2000
+
+	top := make(map[string]string, numKeys)
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("object-%d", i)
+		order := hashOrderHRW(key, nodeIDs)
+		top[key] = nodeIDs[order[0]]
+	}
+
+	removed := nodeIDs[3]
+	remaining := append(append([]string{}, nodeIDs[:3]...), nodeIDs[4:]...)
+
+	var reassigned int
+	for key, oldTop := range top {
+		order := hashOrderHRW(key, remaining)
+		newTop := remaining[order[0]]
+		if oldTop == removed {
+			// Keys previously mapped to the removed node are expected
+			// to move; that's the point of removing it.
+			reassigned++
+			continue
+		}
+		if newTop != oldTop {
+			t.Fatalf("key %q was reassigned from %q to %q after removing unrelated node %q",
+				key, oldTop, newTop, removed)
+		}
+	}
+
+	// Roughly 1/N of keys should have mapped to the removed node.
+	frac := float64(reassigned) / float64(numKeys)
+	want := 1.0 / float64(len(nodeIDs))
+	if frac < want/2 || frac > want*2 {
+		t.Fatalf("expected roughly %.3f of keys to move, got %.3f (%d/%d)", want, frac, reassigned, numKeys)
+	}
+}
+
+func TestHashOrderDisksSelectsAlgorithm(t *testing.T) {
+	nodeIDs := []string{"disk-a", "disk-b", "disk-c", "disk-d"}
+	key := "bucket/object"
+
+	crc := hashOrderDisks(key, len(nodeIDs), hashAlgoCRC, nodeIDs)
+	if !reflect.DeepEqual(crc, hashOrder(key, len(nodeIDs))) {
+		t.Fatalf("hashAlgoCRC should delegate to hashOrder, got %v", crc)
+	}
+
+	hrw := hashOrderDisks(key, len(nodeIDs), hashAlgoHRW, nodeIDs)
+	wantOrder := hashOrderHRW(key, nodeIDs)
+	want := make([]int, len(wantOrder))
+	for rank, idx := range wantOrder {
+		want[idx] = rank + 1
+	}
+	if !reflect.DeepEqual(hrw, want) {
+		t.Fatalf("hashAlgoHRW should delegate to hashOrderHRW, got %v want %v", hrw, want)
+	}
+	// The documented contract: the top-ranked node from hashOrderHRW must
+	// land in block 1, i.e. be shuffled to the front by shuffleDisks.
+	topRanked := wantOrder[0]
+	if hrw[topRanked] != 1 {
+		t.Fatalf("top-ranked node %d should map to block 1, got block %d", topRanked, hrw[topRanked])
+	}
+
+	// A mismatched nodeIDs length falls back to CRC instead of panicking
+	// on an out-of-range index.
+	fallback := hashOrderDisks(key, len(nodeIDs), hashAlgoHRW, nodeIDs[:1])
+	if !reflect.DeepEqual(fallback, hashOrder(key, len(nodeIDs))) {
+		t.Fatalf("mismatched nodeIDs length should fall back to hashOrder, got %v", fallback)
+	}
+}
+
+func TestDistributeXLMetaPrefersTopRankedNode(t *testing.T) {
+	nodeIDs := []string{"disk-a", "disk-b", "disk-c"}
+	key := "bucket/object"
+	disks := make([]StorageAPI, len(nodeIDs))
+	// Tag each slot's metadata with its origin index (via Stat.Size) so
+	// the post-shuffle position can be traced back to it.
+	partsMetadata := make([]xlMetaV1, len(nodeIDs))
+	for i := range partsMetadata {
+		partsMetadata[i].Stat.Size = int64(i)
+	}
+
+	xl := xlObjects{hashAlgorithm: hashAlgoHRW}
+	topRanked := hashOrderHRW(key, nodeIDs)[0]
+
+	_, shuffled := xl.distributeXLMeta(key, disks, partsMetadata, nodeIDs)
+	if shuffled[0].Stat.Size != int64(topRanked) {
+		t.Fatalf("top-ranked node %d should land in block 1, got node %d", topRanked, shuffled[0].Stat.Size)
+	}
+}
+
+func TestPickQuorumXLMetaTieBreaksOnContentHashWhenSizeAndModTimeEqual(t *testing.T) {
+	modTime := time.Now().UTC()
+	a := newTestXLMeta(modTime, 100)
+	b := newTestXLMeta(modTime, 100)
+	b.Meta["x-amz-meta-key"] = "different-value"
+
+	metas := []xlMetaV1{a, a, b, b}
+	errs := make([]error, len(metas))
+
+	hashA := xlMetaCanonicalHash(a)
+	hashB := xlMetaCanonicalHash(b)
+	wantA := bytes.Compare(hashA[:], hashB[:]) > 0
+
+	for i := 0; i < 10; i++ {
+		picked, _, err := pickQuorumXLMeta(metas, errs, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotA := picked.Meta["x-amz-meta-key"] == a.Meta["x-amz-meta-key"]
+		if gotA != wantA {
+			t.Fatalf("expected a deterministic tie-break across repeated calls, got group a=%v want a=%v", gotA, wantA)
+		}
+	}
+}